@@ -2,14 +2,15 @@
 // Use of this source code is governed by a MIT-license
 // that can be found in the LICENSE file.
 
-// Summarize size of directories and files in directories.
+// Summarize size of directories and files in directories, backed by a
+// pluggable FS so real directories, zip archives and tar archives can
+// all be scanned the same way.
 package main
 
 import (
-	"errors"
+	"archive/zip"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -23,13 +24,29 @@ var (
 	sortByAlphabet bool
 	sortBySize     bool
 	sortReverse    bool
+	fsType         string
+	hashEnabled    bool
+	topN           int
+	jobs           int
+	includes       multiFlag
+	excludes       multiFlag
+	maxDepth       int
+	minSizeArg     string
 )
 
-// Parse arguments and show usage.
+// Register flags and the usage message.
 func init() {
 	flag.BoolVar(&sortByAlphabet, "a", false, "sort by Alphabet.")
 	flag.BoolVar(&sortBySize, "s", true, "sort by Size.")
 	flag.BoolVar(&sortReverse, "r", false, "reverse order while sorting.")
+	flag.StringVar(&fsType, "fs", "", `filesystem backend to scan DIR with: "os" (default), "zip" or "tar". Guessed from the file extension when not given.`)
+	flag.BoolVar(&hashEnabled, "hash", false, "print a recursive content digest for each item of the flat listing, cached in ~/.cache/dirsize/hashes.db; the -max-depth tree view and the -top table print none.")
+	flag.IntVar(&topN, "top", 0, "stream results as they're found instead of waiting for the whole scan, keeping only the N largest on a TTY (0 disables streaming).")
+	flag.IntVar(&jobs, "j", 0, "number of goroutine slots for sizing subdirectories (0 means GOMAXPROCS); subdirectories are still sized one at a time.")
+	flag.Var(&includes, "include", "gitignore-style pattern (repeatable) a file must match to be listed (directories are listed regardless); matched against the relative path, ** allowed.")
+	flag.Var(&excludes, "exclude", "gitignore-style pattern (repeatable) that drops a path from the listing; matched against the relative path, ** allowed.")
+	flag.IntVar(&maxDepth, "max-depth", 1, "levels of the tree to expand (1 prints first-level totals, like today; >1 prints an indented tree --du-style view).")
+	flag.StringVar(&minSizeArg, "min-size", "", "omit items smaller than SIZE (e.g. 10M, 1G) from the listing; they're still summed into their parent's total.")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `
 dirsize (v1.1)
@@ -46,10 +63,33 @@ Author: Wei Shen (shenwei356@gmail.com)
 
 `)
 	}
-	flag.Parse()
 }
 
 func main() {
+	flag.Parse()
+
+	minSize, err := parseSize(minSizeArg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	filter := FilterOptions{Include: includes, Exclude: excludes, MinSize: minSize}
+
+	var cache *hashCache
+	if hashEnabled {
+		path, err := defaultHashCachePath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		cache = loadHashCache(path)
+		defer func() {
+			if err := cache.save(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+
 	dirs := flag.Args()
 	if len(dirs) == 0 {
 		dirs = append(dirs, "./")
@@ -64,10 +104,51 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			continue
 		}
-		size, info, err := FolderSize(arg, true)
+		scanner, root, err := newScanner(arg, fsType)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if cache != nil {
+			scanner.EnableHashing(cache)
+		}
+		scanner.SetFilter(filter)
+
+		if maxDepth > 1 {
+			node, err := scanner.Tree(root, maxDepth)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			fmt.Printf("\n%s: %v\n", blue(arg), bytesize.ByteSize(node.Value))
+			for _, child := range node.Children {
+				PrintTree(child, "")
+			}
+			continue
+		}
+
+		items, errc := Walk(scanner, root, WalkOptions{Jobs: jobs})
+
+		if topN > 0 {
+			size, err := streamResults(scanner, arg, items, errc, topN)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			fmt.Printf("\n%s: %v\n", blue(arg), bytesize.ByteSize(size))
+			continue
+		}
+
+		info := make([]Item, 0, 128)
+		for item := range items {
+			info = append(info, item)
+		}
+		if err := <-errc; err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
+		// Use the Scanner's own total, not a sum of the listed items: items
+		// dropped by -include/-exclude/-min-size are still sized, so this
+		// reconciles with plain du even when the listing is filtered.
+		size := scanner.TotalSize()
 		// reverse order while sorting
 		if !sortReverse {
 			if sortByAlphabet { // sort by Alphabet
@@ -85,10 +166,14 @@ func main() {
 
 		fmt.Printf("\n%s: %v\n", blue(arg), bytesize.ByteSize(size))
 		for _, item := range info {
+			name := item.Key
 			if item.IsDir {
-				fmt.Printf("%10v\t%s\n", bytesize.ByteSize(item.Value), blue(item.Key))
+				name = blue(item.Key)
+			}
+			if hashEnabled {
+				fmt.Printf("%10v\t%s\t%s\n", bytesize.ByteSize(item.Value), item.Hash, name)
 			} else {
-				fmt.Printf("%10v\t%s\n", bytesize.ByteSize(item.Value), item.Key)
+				fmt.Printf("%10v\t%s\n", bytesize.ByteSize(item.Value), name)
 			}
 		}
 	}
@@ -96,103 +181,52 @@ func main() {
 
 var blue = color.New(color.FgBlue).SprintFunc()
 
-// FolderSize gets total size of files in a directory,
-// and stores the sizes of first level
-// directories and files in a key-value list.
-func FolderSize(dirname string, firstLevel bool) (int64, []Item, error) {
-	var size int64 = 0
-	var info []Item
-	if firstLevel {
-		info = make([]Item, 0, 128)
-	}
-
-	// Check the read permission
-	f, err := os.Open(dirname)
-	if err != nil {
-		// open-permission-denied file or directory
-		return 0, nil, err
-	}
-	defer f.Close()
-
-	// read info
-	fi, err := f.Stat()
-	if err != nil {
-		return 0, nil, err
-	}
-
-	// it'a a file
-	if !fi.IsDir() {
-		size1 := fi.Size()
-		if firstLevel {
-			info = append(info, Item{dirname, size1, false})
+// newScanner picks the FS backend for arg: an explicit -fs value wins,
+// otherwise it's guessed from the file extension, falling back to the
+// local OS. It also returns the root path to scan within that backend
+// (archives are rooted at "", real paths are rooted at arg itself).
+func newScanner(arg string, kind string) (*Scanner, string, error) {
+	if kind == "" {
+		switch strings.ToLower(filepath.Ext(arg)) {
+		case ".zip":
+			kind = "zip"
+		case ".tar":
+			kind = "tar"
+		default:
+			kind = "os"
 		}
-		return size1, info, nil
-	}
-
-	// it's a directory
-	files, err := ioutil.ReadDir(dirname)
-	if err != nil {
-		return 0, nil, errors.New("read directory error: " + dirname)
 	}
 
-	for _, file := range files {
-		if file.Name() == "." || file.Name() == ".." {
-			continue
+	switch kind {
+	case "zip":
+		zr, err := zip.OpenReader(arg)
+		if err != nil {
+			return nil, "", err
 		}
-		fileFullPath := filepath.Join(dirname, file.Name())
-
-		// file or dir judgement could reduce the compute complexity
-		// file is not worthing call FolderSize
-		if file.IsDir() {
-			size1, _, err := FolderSize(fileFullPath, false)
-			if err != nil {
-				// skip this directory
-				fmt.Fprintf(os.Stderr, "read permission denied (dir): %s\n", fileFullPath)
-				continue
-			}
-			size += size1
-			if firstLevel {
-				info = append(info, Item{file.Name(), size1, true})
-			}
-		} else {
-			mode := file.Mode()
-			// ignore pipe file
-			if strings.HasPrefix(mode.String(), "p") {
-				fmt.Fprintf(os.Stderr, "pipe file ignored: %s\n", fileFullPath)
-				continue
-			}
-			// Check the read permission
-			// DO NOT use ioutil.ReadFile, which will exhaust the RAM!!!!
-			f2, err := os.Open(fileFullPath)
-
-			if err != nil && os.IsPermission(err) {
-				recover()
-				// open-permission-denied file
-				fmt.Fprintf(os.Stderr, "read permission denied (file): %s\n", fileFullPath)
-				continue
-			}
-
-			// to avoid panic "open two many file"
-			// defer df2.Close() did not seccess due to "nil pointer err"
-			if f2 != nil {
-				f2.Close()
-			}
-
-			size1 := file.Size()
-			size += size1
-			if firstLevel {
-				info = append(info, Item{file.Name(), size1, false})
-			}
+		return NewScanner(newZipFS(&zr.Reader, archiveNamespace("zip", arg))), "", nil
+	case "tar":
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		tfs, err := newTarFS(f, archiveNamespace("tar", arg))
+		if err != nil {
+			return nil, "", err
 		}
+		return NewScanner(tfs), "", nil
+	default:
+		return NewScanner(osFS{}), arg, nil
 	}
-	return size, info, nil
 }
 
-// Item records a file and its size
+// Item records a file and its size, plus its recursive content digest
+// when -hash is enabled (empty otherwise).
 type Item struct {
 	Key   string
 	Value int64
 	IsDir bool
+	Hash  string
 }
 
 // ByKey sorts by key
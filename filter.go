@@ -0,0 +1,138 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FilterOptions controls which parts of a tree Scanner lists, independent
+// of how deep a -max-depth tree view goes; filtered-out parts are still
+// descended into and sized.
+type FilterOptions struct {
+	Include []string // gitignore-style patterns; when non-empty, a file must match one to be kept (directories are kept regardless)
+	Exclude []string // gitignore-style patterns; a path matching any of these is dropped
+	MinSize int64    // items smaller than this are omitted from the listing (still summed)
+}
+
+// multiFlag collects repeatable flags (like -include/-exclude) into a
+// []string; it implements flag.Value.
+type multiFlag []string
+
+func (m *multiFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+// excluded reports whether rel should be dropped from the listing given
+// opts: excluded by -exclude, or not matched by -include when any were
+// given. This is for leaves (files, or directories that won't be
+// expanded any further): there's nothing deeper that could still match.
+func (o FilterOptions) excluded(rel string) bool {
+	if len(o.Exclude) > 0 && matchAny(o.Exclude, rel) {
+		return true
+	}
+	if len(o.Include) > 0 && !matchAny(o.Include, rel) {
+		return true
+	}
+	return false
+}
+
+// excludedDir reports whether a directory at rel should be dropped from
+// the listing; callers still descend into it and size it, so totals
+// reconcile with plain du. Unlike excluded, -include alone never drops
+// a directory: -include patterns target files (e.g. "**/*.go"),
+// and a directory whose own name doesn't match one may still hold
+// matching files deeper inside, so only -exclude can drop it wholesale.
+func (o FilterOptions) excludedDir(rel string) bool {
+	return len(o.Exclude) > 0 && matchAny(o.Exclude, rel)
+}
+
+// matchAny reports whether rel matches any of patterns, checked against
+// both the full relative path and, for slash-free patterns, just the
+// base name (gitignore-style).
+func matchAny(patterns []string, rel string) bool {
+	base := filepath.Base(rel)
+	for _, p := range patterns {
+		if matchGlob(p, rel) {
+			return true
+		}
+		if !strings.Contains(p, "/") && matchGlob(p, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeUnits maps the suffixes accepted by -min-size to their byte
+// multiplier, matching bytesize.ByteSize's own binary (1024-based) units.
+var sizeUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// parseSize parses a -min-size value like "10M" or "1G" into bytes. An
+// empty string parses to 0 (no minimum).
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') && s[i-1] != '.' {
+		i--
+	}
+	num, unit := s[:i], strings.ToLower(s[i:])
+	mul, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid -min-size unit %q in %q", s[i:], s)
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid -min-size value %q", s)
+	}
+	return int64(val * float64(mul)), nil
+}
+
+// matchGlob matches pattern against name using path/filepath.Match
+// semantics, with "**" additionally treated as matching any number of
+// whole path segments (doublestar semantics), not just a literal
+// substring. Each non-"**" segment is still matched through
+// filepath.Match, so ordinary wildcards inside it (e.g. "*.go") keep
+// working alongside "**".
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "**") {
+		ok, _ := filepath.Match(pattern, name)
+		return ok
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchGlobSegments matches pattern path segments against name path
+// segments, expanding a "**" segment to zero or more name segments.
+func matchGlobSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		if matchGlobSegments(pattern[1:], name) {
+			return true
+		}
+		return len(name) > 0 && matchGlobSegments(pattern, name[1:])
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], name[0])
+	return ok && matchGlobSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,32 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// File is the subset of *os.File a Scanner needs in order to read file
+// contents; archive-backed implementations wrap their own readers.
+type File interface {
+	io.Reader
+	io.Closer
+}
+
+// FS abstracts the filesystem a Scanner walks. Besides the local OS it is
+// implemented for zip and tar archives (see fs_zip.go and fs_tar.go), and
+// is the seam meant for future remote backends (S3, HTTP, FUSE, ...).
+type FS interface {
+	Open(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+
+	// CacheNamespace identifies this backend instance for -hash's
+	// persistent cache, so a path like "dir/file.txt" inside one zip
+	// archive never collides with the same-looking path from another
+	// archive, or from the real filesystem.
+	CacheNamespace() string
+}
@@ -0,0 +1,159 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tarEntry is a synthetic os.FileInfo for a file or directory inside a
+// tar archive.
+type tarEntry struct {
+	name  string
+	size  int64
+	isDir bool
+	mode  os.FileMode
+}
+
+func (e *tarEntry) Name() string       { return e.name }
+func (e *tarEntry) Size() int64        { return e.size }
+func (e *tarEntry) IsDir() bool        { return e.isDir }
+func (e *tarEntry) ModTime() time.Time { return time.Time{} }
+func (e *tarEntry) Sys() interface{}   { return nil }
+func (e *tarEntry) Mode() os.FileMode {
+	if e.isDir {
+		return e.mode | os.ModeDir
+	}
+	return e.mode
+}
+
+// tarFS indexes an entire tar archive into memory since tar entries can
+// only be read once in stream order; this gives the random access
+// zipFS gets for free, at the cost of buffering file contents.
+type tarFS struct {
+	contents  map[string][]byte
+	entries   map[string][]os.FileInfo
+	namespace string // identifies the backing archive for -hash's cache keys
+}
+
+// newTarFS reads every entry of r into memory and indexes it, synthesizing
+// any intermediate directories the archive didn't record explicitly.
+// namespace identifies the archive backing r (see archiveNamespace) so
+// cached digests don't collide with another archive or the real
+// filesystem.
+func newTarFS(r io.Reader, namespace string) (*tarFS, error) {
+	fsys := &tarFS{
+		contents:  make(map[string][]byte),
+		entries:   make(map[string][]os.FileInfo),
+		namespace: namespace,
+	}
+	seenDir := make(map[string]bool)
+	var addDir func(dir string)
+	addDir = func(dir string) {
+		dir = strings.Trim(path.Clean(dir), "/")
+		if dir == "." || dir == "" || seenDir[dir] {
+			return
+		}
+		seenDir[dir] = true
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		addDir(parent)
+		fsys.entries[parent] = append(fsys.entries[parent], &tarEntry{name: path.Base(dir), isDir: true, mode: 0755})
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.Trim(path.Clean(hdr.Name), "/")
+		if hdr.Typeflag == tar.TypeDir {
+			addDir(name)
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		addDir(dir)
+		buf, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		fsys.contents[name] = buf
+		fsys.entries[dir] = append(fsys.entries[dir], &tarEntry{
+			name: path.Base(name),
+			size: int64(len(buf)),
+			mode: os.FileMode(hdr.Mode),
+		})
+	}
+	for dir, children := range fsys.entries {
+		sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+		fsys.entries[dir] = children
+	}
+	return fsys, nil
+}
+
+func (fsys *tarFS) clean(name string) string {
+	name = path.Clean(filepath.ToSlash(name))
+	if name == "." {
+		return ""
+	}
+	return strings.Trim(name, "/")
+}
+
+func (fsys *tarFS) CacheNamespace() string { return fsys.namespace }
+
+func (fsys *tarFS) Stat(name string) (os.FileInfo, error) {
+	clean := fsys.clean(name)
+	if clean == "" {
+		return &tarEntry{name: "/", isDir: true, mode: 0755}, nil
+	}
+	if buf, ok := fsys.contents[clean]; ok {
+		return &tarEntry{name: path.Base(clean), size: int64(len(buf)), mode: 0644}, nil
+	}
+	if _, ok := fsys.entries[clean]; ok {
+		return &tarEntry{name: path.Base(clean), isDir: true, mode: 0755}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (fsys *tarFS) Open(name string) (File, error) {
+	clean := fsys.clean(name)
+	buf, ok := fsys.contents[clean]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return nopCloser{bytes.NewReader(buf)}, nil
+}
+
+func (fsys *tarFS) ReadDir(name string) ([]os.FileInfo, error) {
+	clean := fsys.clean(name)
+	children, ok := fsys.entries[clean]
+	if !ok && clean != "" {
+		return nil, os.ErrNotExist
+	}
+	return children, nil
+}
+
+// nopCloser adapts a bytes.Reader to the File interface for archive
+// entries that are already buffered in memory.
+type nopCloser struct{ *bytes.Reader }
+
+func (nopCloser) Close() error { return nil }
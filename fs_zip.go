@@ -0,0 +1,141 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// zipEntry is a synthetic os.FileInfo for a file or directory inside a
+// zip archive; directories are inferred from path prefixes since zip
+// archives don't always record them as explicit entries.
+type zipEntry struct {
+	name  string
+	size  int64
+	isDir bool
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (e *zipEntry) Name() string       { return e.name }
+func (e *zipEntry) Size() int64        { return e.size }
+func (e *zipEntry) IsDir() bool        { return e.isDir }
+func (e *zipEntry) ModTime() time.Time { return e.mtime }
+func (e *zipEntry) Sys() interface{}   { return nil }
+func (e *zipEntry) Mode() os.FileMode {
+	if e.isDir {
+		return e.mode | os.ModeDir
+	}
+	return e.mode
+}
+
+// zipFS exposes the contents of a *zip.Reader as an FS, so archives can be
+// scanned for a per-first-level size breakdown without extracting them.
+type zipFS struct {
+	files     map[string]*zip.File
+	entries   map[string][]os.FileInfo
+	namespace string // identifies the backing archive for -hash's cache keys
+}
+
+// newZipFS indexes every entry in r, synthesizing the intermediate
+// directories zip archives often omit. namespace identifies the archive
+// backing r (see archiveNamespace) so cached digests don't collide with
+// another archive or the real filesystem.
+func newZipFS(r *zip.Reader, namespace string) *zipFS {
+	fsys := &zipFS{
+		files:     make(map[string]*zip.File),
+		entries:   make(map[string][]os.FileInfo),
+		namespace: namespace,
+	}
+	seenDir := make(map[string]bool)
+	var addDir func(dir string)
+	addDir = func(dir string) {
+		dir = strings.Trim(path.Clean(dir), "/")
+		if dir == "." || dir == "" || seenDir[dir] {
+			return
+		}
+		seenDir[dir] = true
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		addDir(parent)
+		fsys.entries[parent] = append(fsys.entries[parent], &zipEntry{name: path.Base(dir), isDir: true, mode: 0755})
+	}
+
+	for _, f := range r.File {
+		name := strings.Trim(path.Clean(f.Name), "/")
+		if f.FileInfo().IsDir() {
+			addDir(name)
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		addDir(dir)
+		fsys.files[name] = f
+		fsys.entries[dir] = append(fsys.entries[dir], &zipEntry{
+			name:  path.Base(name),
+			size:  int64(f.UncompressedSize64),
+			mode:  f.Mode(),
+			mtime: f.Modified,
+		})
+	}
+	for dir, children := range fsys.entries {
+		sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+		fsys.entries[dir] = children
+	}
+	return fsys
+}
+
+func (fsys *zipFS) clean(name string) string {
+	name = path.Clean(filepath.ToSlash(name))
+	if name == "." {
+		return ""
+	}
+	return strings.Trim(name, "/")
+}
+
+func (fsys *zipFS) CacheNamespace() string { return fsys.namespace }
+
+func (fsys *zipFS) Stat(name string) (os.FileInfo, error) {
+	clean := fsys.clean(name)
+	if clean == "" {
+		return &zipEntry{name: "/", isDir: true, mode: 0755}, nil
+	}
+	if f, ok := fsys.files[clean]; ok {
+		fi := f.FileInfo()
+		return &zipEntry{name: path.Base(clean), size: fi.Size(), mode: fi.Mode(), mtime: fi.ModTime()}, nil
+	}
+	if _, ok := fsys.entries[clean]; ok {
+		return &zipEntry{name: path.Base(clean), isDir: true, mode: 0755}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (fsys *zipFS) Open(name string) (File, error) {
+	clean := fsys.clean(name)
+	f, ok := fsys.files[clean]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return f.Open()
+}
+
+func (fsys *zipFS) ReadDir(name string) ([]os.FileInfo, error) {
+	clean := fsys.clean(name)
+	children, ok := fsys.entries[clean]
+	if !ok && clean != "" {
+		return nil, os.ErrNotExist
+	}
+	return children, nil
+}
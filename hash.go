@@ -0,0 +1,199 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// hashCacheEntry is one persisted record: the stat tuple a digest was
+// computed from, and the digest itself.
+type hashCacheEntry struct {
+	Size    int64
+	ModTime int64
+	Inode   uint64
+	Digest  string
+}
+
+// hashCache is a persistent, path-keyed store of content digests, so a
+// later `dirsize -hash` run can skip rehashing anything whose stat tuple
+// (size, mtime, inode) hasn't changed.
+type hashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]hashCacheEntry
+	dirty   bool
+}
+
+// archiveNamespace builds a -hash cache namespace for an archive-backed
+// FS from its kind and the archive file's own identity (path, size,
+// mtime), so a cached digest for "dir/file.txt" inside one archive can
+// never be served for a same-named entry in a different (or later
+// rewritten) archive.
+func archiveNamespace(kind, archivePath string) string {
+	abs, err := filepath.Abs(archivePath)
+	if err != nil {
+		abs = archivePath
+	}
+	fi, err := os.Stat(archivePath)
+	if err != nil {
+		return fmt.Sprintf("%s:%s", kind, abs)
+	}
+	return fmt.Sprintf("%s:%s:%d:%d", kind, abs, fi.Size(), fi.ModTime().UnixNano())
+}
+
+// statEntry builds the stat tuple used to validate a cache entry from fi,
+// leaving Digest unset.
+func statEntry(fi os.FileInfo) hashCacheEntry {
+	e := hashCacheEntry{Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+	if sys, ok := fi.Sys().(*syscall.Stat_t); ok {
+		e.Inode = sys.Ino
+	}
+	return e
+}
+
+// defaultHashCachePath returns ~/.cache/dirsize/hashes.db.
+func defaultHashCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "dirsize", "hashes.db"), nil
+}
+
+// loadHashCache reads the cache file at path, if any. A missing or
+// corrupt file just starts an empty cache rather than failing the scan.
+func loadHashCache(path string) *hashCache {
+	c := &hashCache{path: path, entries: make(map[string]hashCacheEntry)}
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+	_ = gob.NewDecoder(f).Decode(&c.entries)
+	return c
+}
+
+// save persists the cache back to disk, if anything changed.
+func (c *hashCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(c.entries)
+}
+
+func (c *hashCache) lookup(key string, stat hashCacheEntry) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Size != stat.Size || e.ModTime != stat.ModTime || e.Inode != stat.Inode {
+		return "", false
+	}
+	return e.Digest, true
+}
+
+func (c *hashCache) store(key string, stat hashCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = stat
+	c.dirty = true
+}
+
+// hashBufPool reuses read buffers across file digests to avoid churning
+// the GC on large trees.
+var hashBufPool = sync.Pool{
+	New: func() interface{} { return make([]byte, 64*1024) },
+}
+
+// cacheKey builds the -hash cache key for path under s.fs: the backend's
+// CacheNamespace (which already disambiguates one archive from another,
+// or from the real filesystem) plus path, cleaned and made absolute when
+// the backend is the real filesystem.
+func (s *Scanner) cacheKey(path string) string {
+	ns := s.fs.CacheNamespace()
+	if ns == "os" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		return ns + "\x00" + filepath.Clean(abs)
+	}
+	return ns + "\x00" + filepath.ToSlash(filepath.Clean(path))
+}
+
+// fileDigest returns the sha256 digest of the file at path, reusing the
+// cached value when its (size, mtime, inode) tuple is unchanged.
+func (s *Scanner) fileDigest(path string, fi os.FileInfo) (string, error) {
+	key := s.cacheKey(path)
+	stat := statEntry(fi)
+
+	if digest, ok := s.hashes.lookup(key, stat); ok {
+		return digest, nil
+	}
+
+	f, err := s.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	buf := hashBufPool.Get().([]byte)
+	defer hashBufPool.Put(buf)
+	if _, err := io.CopyBuffer(h, f, buf); err != nil {
+		return "", err
+	}
+
+	stat.Digest = hex.EncodeToString(h.Sum(nil))
+	s.hashes.store(key, stat)
+	return stat.Digest, nil
+}
+
+// dirDigestInput describes one already-digested child, for combineDigest.
+type dirDigestInput struct {
+	name   string
+	digest string
+	mode   os.FileMode
+	size   int64
+}
+
+// combineDigest computes a directory's recursive content digest from its
+// children, modeled after buildkit's contenthash: a header digest over
+// the sorted (name, mode, size) triples, folded together with each
+// child's own digest (file digest or subtree digest) into the result.
+func combineDigest(children []dirDigestInput) string {
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+
+	header := sha256.New()
+	for _, c := range children {
+		fmt.Fprintf(header, "%s\x00%s\x00%d\n", c.name, c.mode, c.size)
+	}
+
+	content := sha256.New()
+	fmt.Fprintf(content, "%x\n", header.Sum(nil))
+	for _, c := range children {
+		fmt.Fprintf(content, "%s\x00%s\n", c.name, c.digest)
+	}
+	return hex.EncodeToString(content.Sum(nil))
+}
@@ -0,0 +1,192 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Scanner walks a tree through an FS backend, so the same per-first-level
+// size breakdown works for real directories, zip archives and tar
+// archives alike.
+type Scanner struct {
+	fs       FS
+	hashes   *hashCache      // non-nil enables recursive content digests
+	stream   chan<- Item     // non-nil streams first-level Items as they complete
+	walker   *parallelWalker // non-nil sizes subdirectories through a bounded pool of goroutines
+	filter   FilterOptions   // include/exclude/min-size filtering
+	scanRoot string          // root of the current scan, for matching filter patterns
+	total    int64           // dirname's true recursive size from the last firstLevel FolderSize call
+}
+
+// TotalSize returns dirname's true recursive size from the most recent
+// firstLevel FolderSize (or Walk) call, including bytes under entries
+// dropped from the listing by -include/-exclude/-min-size, so it
+// reconciles with plain du.
+func (s *Scanner) TotalSize() int64 {
+	return s.total
+}
+
+// SetFilter installs include/exclude/min-size filtering for this Scanner.
+func (s *Scanner) SetFilter(f FilterOptions) {
+	s.filter = f
+}
+
+// NewScanner creates a Scanner backed by fs.
+func NewScanner(fs FS) *Scanner {
+	return &Scanner{fs: fs}
+}
+
+// EnableHashing turns on recursive content digests backed by cache; a
+// digest is then returned for every Item computed from then on.
+func (s *Scanner) EnableHashing(cache *hashCache) {
+	s.hashes = cache
+}
+
+// FolderSize gets total size of files under dirname, and stores the
+// sizes (and, when hashing is enabled, the content digest) of first
+// level directories and files in a key-value list. It also returns
+// dirname's own recursive content digest, used by the parent call to
+// fold child digests together.
+func (s *Scanner) FolderSize(dirname string, firstLevel bool) (int64, []Item, string, error) {
+	var size int64
+	var info []Item
+	if firstLevel {
+		info = make([]Item, 0, 128)
+		s.scanRoot = dirname
+	}
+
+	fi, err := s.fs.Stat(dirname)
+	if err != nil {
+		// open-permission-denied file or directory
+		return 0, nil, "", err
+	}
+
+	// it's a file
+	if !fi.IsDir() {
+		size1 := fi.Size()
+		var digest string
+		if s.hashes != nil {
+			if digest, err = s.fileDigest(dirname, fi); err != nil {
+				return 0, nil, "", err
+			}
+		}
+		if firstLevel {
+			item := Item{dirname, size1, false, digest}
+			info = append(info, item)
+			if s.stream != nil {
+				s.stream <- item
+			}
+			s.total = size1
+		}
+		return size1, info, digest, nil
+	}
+
+	// it's a directory
+	files, err := s.fs.ReadDir(dirname)
+	if err != nil {
+		return 0, nil, "", errors.New("read directory error: " + dirname)
+	}
+
+	var children []dirDigestInput
+	if s.hashes != nil {
+		children = make([]dirDigestInput, 0, len(files))
+	}
+
+	for _, file := range files {
+		if file.Name() == "." || file.Name() == ".." {
+			continue
+		}
+		fileFullPath := filepath.Join(dirname, file.Name())
+		rel := strings.TrimPrefix(strings.TrimPrefix(fileFullPath, s.scanRoot), string(filepath.Separator))
+
+		// file or dir judgement could reduce the compute complexity
+		// file is not worthing call FolderSize
+		if file.IsDir() {
+			var size1 int64
+			var digest string
+			var err error
+			if s.walker != nil {
+				size1, digest, err = s.walker.size(fileFullPath)
+			} else {
+				size1, _, digest, err = s.FolderSize(fileFullPath, false)
+			}
+			if err != nil {
+				// skip this directory
+				fmt.Fprintf(os.Stderr, "read permission denied (dir): %s\n", fileFullPath)
+				continue
+			}
+			size += size1
+			// excluded dirs are still sized in full above so the parent
+			// total reconciles with plain du; they're just not listed.
+			if firstLevel && !s.filter.excludedDir(rel) && size1 >= s.filter.MinSize {
+				item := Item{file.Name(), size1, true, digest}
+				info = append(info, item)
+				if s.stream != nil {
+					s.stream <- item
+				}
+			}
+			if s.hashes != nil {
+				children = append(children, dirDigestInput{file.Name(), digest, file.Mode(), size1})
+			}
+		} else {
+			mode := file.Mode()
+			// ignore pipe file
+			if strings.HasPrefix(mode.String(), "p") {
+				fmt.Fprintf(os.Stderr, "pipe file ignored: %s\n", fileFullPath)
+				continue
+			}
+			// Check the read permission
+			// DO NOT read the whole file, which will exhaust the RAM!!!!
+			f2, err := s.fs.Open(fileFullPath)
+			if err != nil && os.IsPermission(err) {
+				// open-permission-denied file
+				fmt.Fprintf(os.Stderr, "read permission denied (file): %s\n", fileFullPath)
+				continue
+			}
+			if f2 != nil {
+				f2.Close()
+			}
+
+			size1 := file.Size()
+			var digest string
+			if s.hashes != nil {
+				if digest, err = s.fileDigest(fileFullPath, file); err != nil {
+					fmt.Fprintf(os.Stderr, "hash error (file): %s: %v\n", fileFullPath, err)
+					continue
+				}
+			}
+			size += size1
+			if firstLevel && !s.filter.excluded(rel) && size1 >= s.filter.MinSize {
+				item := Item{file.Name(), size1, false, digest}
+				info = append(info, item)
+				if s.stream != nil {
+					s.stream <- item
+				}
+			}
+			if s.hashes != nil {
+				children = append(children, dirDigestInput{file.Name(), digest, mode, size1})
+			}
+		}
+	}
+
+	var digest string
+	if s.hashes != nil {
+		// dirname's own (size, mtime, inode) doesn't change when a
+		// descendant's content does, so unlike file digests a directory
+		// digest can't be cached and trusted without re-checking every
+		// child: always fold the (already up to date) children digests
+		// instead of risking a stale result.
+		digest = combineDigest(children)
+	}
+	if firstLevel {
+		s.total = size
+	}
+	return size, info, digest, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shenwei356/util/bytesize"
+)
+
+// topHeap is a bounded min-heap over Item.Value, used to track the N
+// largest items seen so far while streaming.
+type topHeap []Item
+
+func (h topHeap) Len() int            { return len(h) }
+func (h topHeap) Less(i, j int) bool  { return h[i].Value < h[j].Value }
+func (h topHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *topHeap) Push(x interface{}) { *h = append(*h, x.(Item)) }
+func (h *topHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// isTTY reports whether f is attached to a terminal.
+func isTTY(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// streamResults consumes items as Walk produces them. On a TTY it keeps a
+// bounded top-N heap and redraws a table in place every tick, so the
+// largest items so far are visible long before a huge tree finishes
+// scanning. On non-TTY output each item is emitted as newline-delimited
+// JSON as soon as it finishes, so `dirsize | head` returns immediately
+// instead of waiting for the whole scan. The returned total is scanner's
+// own recursive size, not a sum of the streamed items: entries dropped by
+// -include/-exclude/-min-size are still sized, so it reconciles with
+// plain du the same way the non-streaming path does.
+func streamResults(scanner *Scanner, label string, items <-chan Item, errc <-chan error, top int) (int64, error) {
+	tty := isTTY(os.Stdout)
+	var h topHeap
+	enc := json.NewEncoder(os.Stdout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	render := func() {
+		sorted := append(topHeap(nil), h...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Value > sorted[j].Value })
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("%s: scanning...\n", blue(label))
+		for _, item := range sorted {
+			name := item.Key
+			if item.IsDir {
+				name = blue(item.Key)
+			}
+			fmt.Printf("%10v\t%s\n", bytesize.ByteSize(item.Value), name)
+		}
+	}
+
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				if tty {
+					render()
+				}
+				return scanner.TotalSize(), <-errc
+			}
+			if tty {
+				heap.Push(&h, item)
+				if top > 0 && h.Len() > top {
+					heap.Pop(&h)
+				}
+			} else {
+				_ = enc.Encode(item)
+			}
+		case <-ticker.C:
+			if tty {
+				render()
+			}
+		}
+	}
+}
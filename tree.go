@@ -0,0 +1,122 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/shenwei356/util/bytesize"
+)
+
+// Node is one entry in a depth-limited tree view: its own Item plus,
+// down to -max-depth levels, its children.
+type Node struct {
+	Item
+	Children []*Node
+}
+
+// Tree builds a depth-limited view of dirname, like `tree --du`:
+// directories are expanded down to maxDepth levels (maxDepth 1 matches
+// FolderSize's flat first-level output). Anything deeper, and anything
+// dropped by the Scanner's filter, is still sized in full via
+// FolderSize so the printed totals reconcile with plain du.
+func (s *Scanner) Tree(dirname string, maxDepth int) (*Node, error) {
+	s.scanRoot = dirname
+	return s.tree(dirname, "", 0, maxDepth)
+}
+
+func (s *Scanner) tree(dirname, rel string, depth, maxDepth int) (*Node, error) {
+	fi, err := s.fs.Stat(dirname)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(dirname)
+	if rel != "" {
+		name = filepath.Base(rel)
+	}
+
+	if !fi.IsDir() {
+		return &Node{Item: Item{Key: name, Value: fi.Size()}}, nil
+	}
+
+	if depth >= maxDepth {
+		size, _, _, err := s.FolderSize(dirname, false)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Item: Item{Key: name, Value: size, IsDir: true}}, nil
+	}
+
+	files, err := s.fs.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{Item: Item{Key: name, IsDir: true}}
+	for _, file := range files {
+		if file.Name() == "." || file.Name() == ".." {
+			continue
+		}
+		childPath := filepath.Join(dirname, file.Name())
+		childRel := filepath.Join(rel, file.Name())
+
+		if file.Mode()&os.ModeNamedPipe != 0 {
+			continue
+		}
+
+		if file.IsDir() {
+			// Only -exclude prunes a directory outright: -include
+			// targets files, and a directory that doesn't match one
+			// itself may still hold matching files deeper inside, so
+			// it's always expanded and let its children filter
+			// themselves.
+			if s.filter.excludedDir(childRel) {
+				// still sized so the parent total reconciles with plain
+				// du, just not expanded into the printed tree.
+				size, _, _, err := s.FolderSize(childPath, false)
+				if err != nil {
+					continue
+				}
+				node.Value += size
+				continue
+			}
+			child, err := s.tree(childPath, childRel, depth+1, maxDepth)
+			if err != nil {
+				continue
+			}
+			node.Value += child.Value
+			if child.Value >= s.filter.MinSize {
+				node.Children = append(node.Children, child)
+			}
+		} else {
+			size1 := file.Size()
+			node.Value += size1
+			if s.filter.excluded(childRel) {
+				continue
+			}
+			if size1 >= s.filter.MinSize {
+				node.Children = append(node.Children, &Node{Item: Item{Key: file.Name(), Value: size1}})
+			}
+		}
+	}
+	sort.Slice(node.Children, func(i, j int) bool { return node.Children[i].Value > node.Children[j].Value })
+	return node, nil
+}
+
+// PrintTree renders node as an indented tree (like `tree --du`), with
+// each directory's own total printed alongside its name.
+func PrintTree(node *Node, indent string) {
+	name := node.Key
+	if node.IsDir {
+		name = blue(node.Key)
+	}
+	fmt.Printf("%s%10v  %s\n", indent, bytesize.ByteSize(node.Value), name)
+	for _, child := range node.Children {
+		PrintTree(child, indent+"  ")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2013-2020 Wei Shen (shenwei356@gmail.com). All rights reserved.
+// Use of this source code is governed by a MIT-license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WalkOptions configures Walk.
+type WalkOptions struct {
+	Jobs int // max concurrent sizing goroutines; <=0 means GOMAXPROCS
+}
+
+// walkTaskResult is the outcome of sizing one subdirectory.
+type walkTaskResult struct {
+	size   int64
+	digest string
+	err    error
+}
+
+// parallelWalker sizes subdirectories through a semaphore of n slots:
+// size hands a path to a fresh goroutine while a slot is free and sizes
+// it inline in the calling goroutine once every slot is taken. Either
+// way size blocks until that path is sized, so sibling subdirectories
+// are sized one after another, not concurrently; the slots bound only
+// how many nested sizing goroutines exist at once. A caller only ever
+// waits on a goroutine it directly started, so the wait graph is a tree,
+// not a cycle, and deep trees can't deadlock the pool.
+type parallelWalker struct {
+	scanner *Scanner
+	sem     chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newParallelWalker creates a walker for scanner allowing up to n
+// concurrent sizing goroutines (GOMAXPROCS if n<=0).
+func newParallelWalker(scanner *Scanner, n int) *parallelWalker {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &parallelWalker{scanner: scanner, sem: make(chan struct{}, n)}
+}
+
+// size sizes path, spawning a goroutine for it when a semaphore slot is
+// free and falling back to sizing it inline otherwise. It returns only
+// once path is fully sized.
+func (w *parallelWalker) size(path string) (int64, string, error) {
+	select {
+	case w.sem <- struct{}{}:
+		w.wg.Add(1)
+		reply := make(chan walkTaskResult, 1)
+		go func() {
+			defer w.wg.Done()
+			defer func() { <-w.sem }()
+			size, _, digest, err := w.scanner.FolderSize(path, false)
+			reply <- walkTaskResult{size, digest, err}
+		}()
+		r := <-reply
+		return r.size, r.digest, r.err
+	default:
+		size, _, digest, err := w.scanner.FolderSize(path, false)
+		return size, digest, err
+	}
+}
+
+func (w *parallelWalker) close() {
+	w.wg.Wait()
+}
+
+// Walk sizes root through a parallelWalker with WalkOptions.Jobs slots
+// (subdirectories are still sized one at a time) and streams first-level
+// Items into the returned channel as they complete;
+// the error channel receives root's scan error (if any) once the walk
+// finishes. It's the reusable API behind both the CLI and future library
+// users.
+func Walk(scanner *Scanner, root string, opts WalkOptions) (<-chan Item, <-chan error) {
+	items := make(chan Item)
+	errc := make(chan error, 1)
+	w := newParallelWalker(scanner, opts.Jobs)
+	scanner.stream = items
+	scanner.walker = w
+	go func() {
+		defer close(items)
+		defer w.close()
+		_, _, _, err := scanner.FolderSize(root, true)
+		errc <- err
+	}()
+	return items, errc
+}